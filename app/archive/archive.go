@@ -0,0 +1,121 @@
+// Package archive extracts tar archives, with or without gzip/xz/zstd
+// compression, into a destination directory while guarding against path
+// traversal, symlink escapes and zip-bomb-style resource exhaustion.
+package archive
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ExtractOptions bounds what Extract will write to disk. A zero value
+// leaves both limits unenforced.
+type ExtractOptions struct {
+	// MaxTotalSize caps the sum of all extracted file sizes.
+	MaxTotalSize int64
+	// MaxFileSize caps any single extracted file's size.
+	MaxFileSize int64
+}
+
+// Extract reads a tar stream from r -- auto-detecting gzip, xz or zstd
+// compression from its magic bytes -- and writes its contents under
+// destDir, which must already exist.
+//
+// Every entry's cleaned path, and the resolved target of any
+// symlink/hardlink entry, is checked to stay within destDir (the ZipSlip
+// check); entries that escape it, that exceed opts' size limits, or that
+// are of any type other than directory/regular-file/symlink/hardlink are
+// rejected and extraction stops.
+func Extract(r io.Reader, destDir string, opts ExtractOptions) error {
+	stream, err := decompress(r)
+	if err != nil {
+		return fmt.Errorf("archive: %s", err)
+	}
+
+	tr := tar.NewReader(stream)
+	var total int64
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("archive: %s", err)
+		}
+
+		target, err := safeJoin(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, header.FileInfo().Mode()); err != nil {
+				return fmt.Errorf("archive: mkdir %s: %s", header.Name, err)
+			}
+		case tar.TypeReg:
+			if opts.MaxFileSize > 0 && header.Size > opts.MaxFileSize {
+				return fmt.Errorf("archive: %s is %d bytes, exceeds max file size %d bytes", header.Name, header.Size, opts.MaxFileSize)
+			}
+			total += header.Size
+			if opts.MaxTotalSize > 0 && total > opts.MaxTotalSize {
+				return fmt.Errorf("archive: extracted size exceeds max total size %d bytes", opts.MaxTotalSize)
+			}
+			if err := writeFile(target, tr, header); err != nil {
+				return fmt.Errorf("archive: write %s: %s", header.Name, err)
+			}
+		case tar.TypeSymlink, tar.TypeLink:
+			if err := writeLink(destDir, target, header); err != nil {
+				return fmt.Errorf("archive: link %s: %s", header.Name, err)
+			}
+		default:
+			return fmt.Errorf("archive: unsupported entry type %v for %s", header.Typeflag, header.Name)
+		}
+	}
+}
+
+// writeFile extracts a regular file entry, preserving its mode and mtime.
+func writeFile(target string, r io.Reader, header *tar.Header) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// writeLink extracts a symlink or hardlink entry, refusing to create it
+// when its resolved target would escape destDir.
+func writeLink(destDir, target string, header *tar.Header) error {
+	var resolved string
+	if filepath.IsAbs(header.Linkname) {
+		resolved = header.Linkname
+	} else {
+		resolved = filepath.Join(filepath.Dir(target), header.Linkname)
+	}
+	if !within(destDir, resolved) {
+		return fmt.Errorf("%q -> %q escapes destination directory", header.Name, header.Linkname)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	_ = os.Remove(target) // a prior entry may have created a stale file at this path
+
+	if header.Typeflag == tar.TypeSymlink {
+		return os.Symlink(header.Linkname, target)
+	}
+	return os.Link(resolved, target)
+}
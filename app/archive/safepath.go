@@ -0,0 +1,26 @@
+package archive
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// safeJoin resolves name against destDir and rejects it -- the ZipSlip
+// check -- unless the cleaned result stays within destDir.
+func safeJoin(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, name)
+	if !within(destDir, target) {
+		return "", fmt.Errorf("archive: entry %q escapes destination directory", name)
+	}
+	return target, nil
+}
+
+// within reports whether target is destDir itself or a descendant of it.
+func within(destDir, target string) bool {
+	rel, err := filepath.Rel(destDir, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
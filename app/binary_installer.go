@@ -0,0 +1,108 @@
+package app
+
+import (
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// BinaryInstaller installs a freshly-downloaded agent binary in place of
+// the one currently running.
+type BinaryInstaller interface {
+	Install(newPath string) error
+}
+
+// defaultInstaller implements BinaryInstaller the way inconshreveable's
+// go-update does it: the new binary is staged in a sibling temp file in
+// the same directory as the target (so the later rename stays on one
+// filesystem and is therefore atomic), fsync'd, and swapped into place
+// with os.Rename. The previous binary is kept as a ".old" sibling until
+// the swap succeeds, so a failed install rolls back instead of leaving
+// the host without a binary.
+type defaultInstaller struct {
+	targetPath string
+}
+
+// NewDefaultInstaller returns a BinaryInstaller that replaces the binary
+// currently running this process. The target path is detected via
+// os.Executable rather than hard-coded, so it works no matter where pgSCV
+// was installed from (/usr/bin, a Docker image layer, a Windows Program
+// Files directory, ...).
+func NewDefaultInstaller() (BinaryInstaller, error) {
+	targetPath, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("resolve running executable path: %s", err)
+	}
+	targetPath, err = filepath.EvalSymlinks(targetPath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve running executable path: %s", err)
+	}
+	return &defaultInstaller{targetPath: targetPath}, nil
+}
+
+// Install atomically replaces the target binary with the one at newPath.
+func (d *defaultInstaller) Install(newPath string) error {
+	staged, err := stageBinary(newPath, filepath.Dir(d.targetPath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(staged) // no-op once renamed into place below
+
+	oldPath := d.targetPath + ".old"
+	_ = os.Remove(oldPath) // best effort; a stale backup may remain from a prior update
+
+	if err := os.Rename(d.targetPath, oldPath); err != nil {
+		return fmt.Errorf("back up current binary: %s", err)
+	}
+
+	if err := os.Rename(staged, d.targetPath); err != nil {
+		if rerr := os.Rename(oldPath, d.targetPath); rerr != nil {
+			return fmt.Errorf("install new binary: %s (rollback also failed: %s)", err, rerr)
+		}
+		return fmt.Errorf("install new binary: %s (rolled back)", err)
+	}
+
+	// the backup is only needed until the new binary is confirmed in place
+	if err := os.Remove(oldPath); err != nil {
+		log.Warn().Err(err).Msg("failed to remove backed up binary, ignore it")
+	}
+	return nil
+}
+
+// stageBinary copies src into a new temp file in dir, fsyncs and chmods
+// it executable, and returns its path for a subsequent atomic rename.
+func stageBinary(src, dir string) (string, error) {
+	from, err := os.Open(src)
+	if err != nil {
+		return "", fmt.Errorf("open new binary: %s", err)
+	}
+	defer from.Close()
+
+	to, err := os.CreateTemp(dir, ".weaponry-agent-new-*")
+	if err != nil {
+		return "", fmt.Errorf("create staging file: %s", err)
+	}
+
+	if _, err := io.Copy(to, from); err != nil {
+		to.Close()
+		os.Remove(to.Name())
+		return "", fmt.Errorf("stage new binary: %s", err)
+	}
+	if err := to.Sync(); err != nil {
+		to.Close()
+		os.Remove(to.Name())
+		return "", fmt.Errorf("sync staging file: %s", err)
+	}
+	if err := to.Chmod(0755); err != nil {
+		to.Close()
+		os.Remove(to.Name())
+		return "", fmt.Errorf("chmod staging file: %s", err)
+	}
+	if err := to.Close(); err != nil {
+		os.Remove(to.Name())
+		return "", fmt.Errorf("close staging file: %s", err)
+	}
+	return to.Name(), nil
+}
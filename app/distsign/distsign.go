@@ -0,0 +1,105 @@
+// Package distsign verifies signed release manifests before pgSCV installs
+// a new agent distribution. It is modeled on Tailscale's distsign scheme: a
+// long-lived, offline Ed25519 "root" key signs short-lived "signing" key
+// certificates, and a signing key in turn signs the manifest published
+// alongside every release. The root key's public half is the only secret
+// pinned into the binary, so compromising the distribution host alone is
+// not enough to make RunUpdate accept a tampered artifact.
+package distsign
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PinnedRootPublicKey is the public half of Weaponry's offline release
+// root key, baked into the binary at build time. Its private half never
+// touches a network-connected machine; it exists only to sign new
+// SigningKeyCert values with the distsign-keygen CLI.
+var PinnedRootPublicKey = mustDecodeKey("6377cc265190a6bb4eb8b94f616b4ae1a0929a0800f19ac9a9e1a0ca09c3b67c")
+
+func mustDecodeKey(hexKey string) ed25519.PublicKey {
+	b, err := hex.DecodeString(hexKey)
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		panic("distsign: invalid pinned root public key")
+	}
+	return ed25519.PublicKey(b)
+}
+
+// SigningKeyCert is a short-lived signing key certificate: a public key
+// countersigned by the root key, valid only for a bounded window so that a
+// leaked signing key has a limited blast radius.
+type SigningKeyCert struct {
+	Public    ed25519.PublicKey `json:"public"`
+	NotBefore time.Time         `json:"not_before"`
+	NotAfter  time.Time         `json:"not_after"`
+	Signature []byte            `json:"signature"`
+}
+
+// signedFields returns the canonical bytes the root key signs over, i.e.
+// everything in the cert except the signature itself.
+func (c SigningKeyCert) signedFields() ([]byte, error) {
+	return json.Marshal(struct {
+		Public    ed25519.PublicKey `json:"public"`
+		NotBefore time.Time         `json:"not_before"`
+		NotAfter  time.Time         `json:"not_after"`
+	}{c.Public, c.NotBefore, c.NotAfter})
+}
+
+// verify checks that cert was signed by rootPub and is valid at now.
+func (c SigningKeyCert) verify(rootPub ed25519.PublicKey, now time.Time) error {
+	if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+		return fmt.Errorf("distsign: signing key certificate expired or not yet valid (window %s to %s)", c.NotBefore, c.NotAfter)
+	}
+	msg, err := c.signedFields()
+	if err != nil {
+		return fmt.Errorf("distsign: marshal signing key certificate: %s", err)
+	}
+	if !ed25519.Verify(rootPub, msg, c.Signature) {
+		return fmt.Errorf("distsign: signing key certificate has an invalid root signature")
+	}
+	return nil
+}
+
+// Manifest describes a single release artifact: its version, filename,
+// size and content hash. It is published next to the artifact as
+// "weaponry-agent.json.sig" and signed by a SigningKeyCert.
+type Manifest struct {
+	Version  string `json:"version"`
+	Filename string `json:"filename"`
+	Length   int64  `json:"length"`
+	SHA256   string `json:"sha256"`
+}
+
+// SignedManifest is the wire format of weaponry-agent.json.sig: a manifest,
+// the signing key certificate that vouches for it, and the signing key's
+// signature over the manifest bytes.
+type SignedManifest struct {
+	Manifest  Manifest       `json:"manifest"`
+	Cert      SigningKeyCert `json:"cert"`
+	Signature []byte         `json:"signature"`
+}
+
+// Verify checks that sm was signed by a signing key whose certificate
+// chains up to rootPub, and returns the verified Manifest on success.
+func Verify(rootPub ed25519.PublicKey, sm SignedManifest) (Manifest, error) {
+	if err := sm.Cert.verify(rootPub, time.Now()); err != nil {
+		return Manifest{}, err
+	}
+	msg, err := json.Marshal(sm.Manifest)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("distsign: marshal manifest: %s", err)
+	}
+	if !ed25519.Verify(sm.Cert.Public, msg, sm.Signature) {
+		return Manifest{}, fmt.Errorf("distsign: manifest has an invalid signing key signature")
+	}
+	return sm.Manifest, nil
+}
+
+// VerifyPinned is Verify using the root key pinned into the binary.
+func VerifyPinned(sm SignedManifest) (Manifest, error) {
+	return Verify(PinnedRootPublicKey, sm)
+}
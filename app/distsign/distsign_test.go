@@ -0,0 +1,126 @@
+package distsign
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testChain holds a root key pair and a signing key certified by it, valid
+// for the given window, so tests can build SignedManifest fixtures without
+// touching the real pinned root key.
+type testChain struct {
+	rootPub  ed25519.PublicKey
+	signPub  ed25519.PublicKey
+	signPriv ed25519.PrivateKey
+	cert     SigningKeyCert
+}
+
+func newTestChain(t *testing.T, notBefore, notAfter time.Time) testChain {
+	t.Helper()
+
+	rootPub, rootPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate root key: %s", err)
+	}
+	signPub, signPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate signing key: %s", err)
+	}
+
+	cert := SigningKeyCert{Public: signPub, NotBefore: notBefore, NotAfter: notAfter}
+	msg, err := cert.signedFields()
+	if err != nil {
+		t.Fatalf("marshal signed fields: %s", err)
+	}
+	cert.Signature = ed25519.Sign(rootPriv, msg)
+
+	return testChain{rootPub: rootPub, signPub: signPub, signPriv: signPriv, cert: cert}
+}
+
+func (c testChain) sign(t *testing.T, manifest Manifest) SignedManifest {
+	t.Helper()
+
+	msg, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("marshal manifest: %s", err)
+	}
+	return SignedManifest{
+		Manifest:  manifest,
+		Cert:      c.cert,
+		Signature: ed25519.Sign(c.signPriv, msg),
+	}
+}
+
+func validManifest() Manifest {
+	return Manifest{Version: "1.2.3", Filename: "weaponry-agent.tar.gz", Length: 1024, SHA256: "deadbeef"}
+}
+
+func TestVerify_ValidChain(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	sm := chain.sign(t, validManifest())
+
+	got, err := Verify(chain.rootPub, sm)
+	if err != nil {
+		t.Fatalf("Verify: unexpected error: %s", err)
+	}
+	if got != validManifest() {
+		t.Fatalf("Verify: got manifest %+v, want %+v", got, validManifest())
+	}
+}
+
+func TestVerify_TamperedManifest(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	sm := chain.sign(t, validManifest())
+
+	sm.Manifest.SHA256 = "tampered"
+
+	if _, err := Verify(chain.rootPub, sm); err == nil {
+		t.Fatal("Verify: expected error for manifest tampered with after signing, got nil")
+	}
+}
+
+func TestVerify_ExpiredCert(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	sm := chain.sign(t, validManifest())
+
+	if _, err := Verify(chain.rootPub, sm); err == nil {
+		t.Fatal("Verify: expected error for expired signing key certificate, got nil")
+	}
+}
+
+func TestVerify_WrongRoot(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	sm := chain.sign(t, validManifest())
+
+	otherRootPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate other root key: %s", err)
+	}
+
+	if _, err := Verify(otherRootPub, sm); err == nil {
+		t.Fatal("Verify: expected error for cert signed by a different root key, got nil")
+	}
+}
+
+func TestFetchSignedManifest(t *testing.T) {
+	chain := newTestChain(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	sm := chain.sign(t, validManifest())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(sm)
+	}))
+	defer srv.Close()
+
+	got, err := FetchSignedManifest(context.Background(), srv.Client(), srv.URL)
+	if err != nil {
+		t.Fatalf("FetchSignedManifest: unexpected error: %s", err)
+	}
+	if _, err := Verify(chain.rootPub, got); err != nil {
+		t.Fatalf("Verify on fetched manifest: unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,34 @@
+package distsign
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// FetchSignedManifest downloads and JSON-decodes the signed manifest at
+// url. It does not verify the signature; call Verify or VerifyPinned on
+// the result before trusting anything in it.
+func FetchSignedManifest(ctx context.Context, client *http.Client, url string) (SignedManifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return SignedManifest{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return SignedManifest{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return SignedManifest{}, fmt.Errorf("distsign: fetch manifest: unexpected status %s", resp.Status)
+	}
+
+	var sm SignedManifest
+	if err := json.NewDecoder(resp.Body).Decode(&sm); err != nil {
+		return SignedManifest{}, fmt.Errorf("distsign: decode manifest: %s", err)
+	}
+	return sm, nil
+}
@@ -0,0 +1,87 @@
+package distsign
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// RunKeygenCLI implements the "distsign-keygen" subcommand used by
+// maintainers to generate the offline root key and to rotate short-lived
+// signing keys for each release. It is not yet wired up to a "pgscv
+// distsign-keygen" entry point in the agent's main command dispatcher --
+// that is tracked as follow-up work; for now, invoke it from a small
+// throwaway main() or via `go run` on the release build machine.
+//
+// Usage:
+//
+//	pgscv distsign-keygen root                                   generate a new root keypair
+//	pgscv distsign-keygen sign <root-priv-hex> <valid-for-days>   generate and sign a new signing keypair
+func RunKeygenCLI(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("distsign-keygen: expected a subcommand, one of: root, sign")
+	}
+
+	switch args[0] {
+	case "root":
+		return runKeygenRoot()
+	case "sign":
+		if len(args) != 3 {
+			return fmt.Errorf("distsign-keygen sign: usage: sign <root-priv-hex> <valid-for-days>")
+		}
+		return runKeygenSign(args[1], args[2])
+	default:
+		return fmt.Errorf("distsign-keygen: unknown subcommand %q", args[0])
+	}
+}
+
+func runKeygenRoot() error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("distsign-keygen: generate root key: %s", err)
+	}
+	fmt.Fprintf(os.Stdout, "root public key  (embed as distsign.PinnedRootPublicKey): %s\n", hex.EncodeToString(pub))
+	fmt.Fprintf(os.Stdout, "root private key (store offline, never commit):           %s\n", hex.EncodeToString(priv))
+	return nil
+}
+
+func runKeygenSign(rootPrivHex, validForDays string) error {
+	rootPrivBytes, err := hex.DecodeString(rootPrivHex)
+	if err != nil || len(rootPrivBytes) != ed25519.PrivateKeySize {
+		return fmt.Errorf("distsign-keygen: invalid root private key")
+	}
+	rootPriv := ed25519.PrivateKey(rootPrivBytes)
+
+	var days int
+	if _, err := fmt.Sscanf(validForDays, "%d", &days); err != nil || days <= 0 {
+		return fmt.Errorf("distsign-keygen: invalid validity period %q", validForDays)
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("distsign-keygen: generate signing key: %s", err)
+	}
+
+	cert := SigningKeyCert{
+		Public:    pub,
+		NotBefore: time.Now(),
+		NotAfter:  time.Now().Add(time.Duration(days) * 24 * time.Hour),
+	}
+	msg, err := cert.signedFields()
+	if err != nil {
+		return fmt.Errorf("distsign-keygen: marshal certificate: %s", err)
+	}
+	cert.Signature = ed25519.Sign(rootPriv, msg)
+
+	out, err := json.MarshalIndent(cert, "", "  ")
+	if err != nil {
+		return fmt.Errorf("distsign-keygen: marshal certificate: %s", err)
+	}
+	fmt.Fprintf(os.Stdout, "signing key certificate (ship with the release):\n%s\n", out)
+	fmt.Fprintf(os.Stdout, "signing private key (keep with the release build machine, discard after signing): %s\n", hex.EncodeToString(priv))
+	return nil
+}
@@ -0,0 +1,18 @@
+package app
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// updateAvailableGauge reports a newly-observed version in CheckOnly mode,
+// so operators can drive an actual upgrade through their own config
+// management instead of pgSCV installing it unattended.
+var updateAvailableGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "pgscv_update_available",
+		Help: "Set to 1 for a version once RunUpdate finds it available while running in check-only mode.",
+	},
+	[]string{"version"},
+)
+
+func init() {
+	prometheus.MustRegister(updateAvailableGauge)
+}
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"encoding/json"
+	"github.com/rs/zerolog/log"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+)
+
+// rolloutStateFile persists rolloutFirstSeen across agent restarts. Without
+// it, a host that restarts (crash, deploy, manual bounce) more often than
+// RolloutWindow would appear to see every version for the first time on
+// every restart, and its rollout delay would never elapse.
+const rolloutStateFile = tmpDir + "/weaponry-agent-rollout.json"
+
+// rolloutFirstSeen remembers, per version string, the moment this host
+// first observed it as available. It's process-global rather than
+// threaded through Config because there is only ever one auto-update loop
+// per agent process; it's seeded from, and persisted to, rolloutStateFile
+// so the clock survives a process restart.
+var (
+	rolloutFirstSeen sync.Map // map[string]time.Time
+	rolloutStateOnce sync.Once
+	rolloutStateMu   sync.Mutex
+)
+
+type rolloutState struct {
+	FirstSeen map[string]time.Time `json:"first_seen"`
+}
+
+// loadRolloutState seeds rolloutFirstSeen from rolloutStateFile, if one
+// exists. A missing or unreadable file just means every version looks new
+// to this process, same as before persistence existed.
+func loadRolloutState() {
+	data, err := os.ReadFile(rolloutStateFile)
+	if err != nil {
+		return
+	}
+	var st rolloutState
+	if err := json.Unmarshal(data, &st); err != nil {
+		log.Warn().Err(err).Msg("rollout: ignoring corrupt rollout state file")
+		return
+	}
+	for version, firstSeen := range st.FirstSeen {
+		rolloutFirstSeen.LoadOrStore(version, firstSeen)
+	}
+}
+
+// saveRolloutState writes the current rolloutFirstSeen contents to
+// rolloutStateFile so a future restart of this process picks up where it
+// left off.
+func saveRolloutState() {
+	st := rolloutState{FirstSeen: make(map[string]time.Time)}
+	rolloutFirstSeen.Range(func(k, v interface{}) bool {
+		st.FirstSeen[k.(string)] = v.(time.Time)
+		return true
+	})
+
+	data, err := json.Marshal(st)
+	if err != nil {
+		log.Warn().Err(err).Msg("rollout: failed to marshal rollout state")
+		return
+	}
+
+	rolloutStateMu.Lock()
+	defer rolloutStateMu.Unlock()
+	if err := os.WriteFile(rolloutStateFile, data, 0644); err != nil {
+		log.Warn().Err(err).Msg("rollout: failed to persist rollout state")
+	}
+}
+
+// rolloutReady reports whether version, first observed at some point in
+// the past, has waited out this host's slice of window. Every host in a
+// fleet picks a different, but stable, delay inside window (derived from
+// its hostname) so a bad release surfaces gradually across (say) 6 hours
+// instead of landing on every host within one polling interval.
+func rolloutReady(version string, window time.Duration) bool {
+	if window <= 0 {
+		return true
+	}
+	rolloutStateOnce.Do(loadRolloutState)
+
+	now := time.Now()
+	firstSeenAny, loaded := rolloutFirstSeen.LoadOrStore(version, now)
+	firstSeen := firstSeenAny.(time.Time)
+	if !loaded {
+		log.Debug().Msgf("rollout: first saw version %s at %s, this host's window closes at %s",
+			version, firstSeen, firstSeen.Add(hostRolloutDelay(window)))
+		saveRolloutState()
+	}
+
+	return now.Sub(firstSeen) >= hostRolloutDelay(window)
+}
+
+// hostRolloutDelay deterministically maps this host's hostname into
+// [0, window), so repeated calls (and restarts of the agent) always
+// agree on the same delay for the same window.
+func hostRolloutDelay(window time.Duration) time.Duration {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(hostname))
+	return time.Duration(h.Sum64() % uint64(window))
+}
@@ -0,0 +1,11 @@
+package app
+
+import "context"
+
+// ServiceManager restarts the pgSCV service after a binary update, using
+// whatever init system or process supervisor manages it on this host.
+// NewServiceManager returns the implementation appropriate for the
+// platform this binary was built for; see service_manager_<os>.go.
+type ServiceManager interface {
+	Restart(ctx context.Context) error
+}
@@ -0,0 +1,30 @@
+//go:build darwin
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"os/exec"
+)
+
+// launchdServiceLabel is the launchd label pgSCV is loaded under.
+const launchdServiceLabel = "io.weaponry.agent"
+
+// NewServiceManager returns a ServiceManager that restarts pgSCV through launchd.
+func NewServiceManager() ServiceManager {
+	return launchdServiceManager{}
+}
+
+// launchdServiceManager restarts the agent via launchctl.
+type launchdServiceManager struct{}
+
+// Restart implements ServiceManager.
+func (launchdServiceManager) Restart(ctx context.Context) error {
+	log.Debug().Msg("restarting the service via launchd")
+	if err := exec.CommandContext(ctx, "launchctl", "kickstart", "-k", "system/"+launchdServiceLabel).Run(); err != nil {
+		return fmt.Errorf("launchctl kickstart failed: %s", err)
+	}
+	return nil
+}
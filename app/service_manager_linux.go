@@ -0,0 +1,63 @@
+//go:build linux
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"os"
+	"os/exec"
+)
+
+// NewServiceManager picks self-reexec when the agent looks like it's
+// running in a container (Docker, a Kubernetes sidecar, ...) with no
+// service supervisor of its own to ask for a restart, systemd when it is
+// running as the host's init system (the overwhelming majority of modern
+// distros otherwise), and falls back to the sysvinit/OpenRC "service"
+// wrapper if neither is present.
+func NewServiceManager() ServiceManager {
+	if runningInContainer() {
+		return NewSelfReexecServiceManager()
+	}
+	if _, err := os.Stat("/run/systemd/system"); err == nil {
+		return systemdServiceManager{}
+	}
+	return sysvServiceManager{}
+}
+
+// runningInContainer reports whether the agent appears to be running
+// inside a container rather than directly on a host: Docker (and most
+// other engines) drop a /.dockerenv marker file into the root filesystem,
+// and Kubernetes always sets KUBERNETES_SERVICE_HOST in a pod's
+// environment, whether or not the container runtime does.
+func runningInContainer() bool {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return true
+	}
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != ""
+}
+
+// systemdServiceManager restarts the agent via systemctl.
+type systemdServiceManager struct{}
+
+// Restart implements ServiceManager.
+func (systemdServiceManager) Restart(ctx context.Context) error {
+	log.Debug().Msg("restarting the service via systemd")
+	if err := exec.CommandContext(ctx, "systemctl", "restart", systemdServiceName).Run(); err != nil {
+		return fmt.Errorf("systemctl restart failed: %s", err)
+	}
+	return nil
+}
+
+// sysvServiceManager restarts the agent via the sysvinit/OpenRC "service" wrapper.
+type sysvServiceManager struct{}
+
+// Restart implements ServiceManager.
+func (sysvServiceManager) Restart(ctx context.Context) error {
+	log.Debug().Msg("restarting the service via sysvinit/OpenRC")
+	if err := exec.CommandContext(ctx, "service", "weaponry-agent", "restart").Run(); err != nil {
+		return fmt.Errorf("service restart failed: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows && !darwin
+
+package app
+
+// NewServiceManager falls back to self-reexec on platforms (FreeBSD,
+// OpenBSD, ...) that have no dedicated ServiceManager implementation yet.
+func NewServiceManager() ServiceManager {
+	return NewSelfReexecServiceManager()
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// selfReexecServiceManager "restarts" the agent by exec-ing the (now
+// updated) binary in place of the current process rather than asking an
+// init system to restart a unit. It is the right choice for containerized
+// deployments (Docker, Kubernetes sidecars) that have no service
+// supervisor at all: the container's PID 1 just keeps watching the same
+// PID as it re-execs into the new image.
+type selfReexecServiceManager struct{}
+
+// NewSelfReexecServiceManager returns a ServiceManager for hosts with no
+// service supervisor. NewServiceManager auto-detects this on Linux
+// containers and on platforms (FreeBSD, OpenBSD, ...) with no dedicated
+// ServiceManager implementation of their own; callers on other platforms
+// can still reach for it directly if they know their deployment has no
+// supervisor.
+func NewSelfReexecServiceManager() ServiceManager {
+	return selfReexecServiceManager{}
+}
+
+// Restart implements ServiceManager.
+func (selfReexecServiceManager) Restart(_ context.Context) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("resolve running executable path: %s", err)
+	}
+	if err := syscall.Exec(exe, os.Args, os.Environ()); err != nil {
+		return fmt.Errorf("re-exec %s: %s", exe, err)
+	}
+	return nil
+}
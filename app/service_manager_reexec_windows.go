@@ -0,0 +1,10 @@
+//go:build windows
+
+package app
+
+// NewSelfReexecServiceManager is not supported on Windows: there is no
+// syscall.Exec equivalent that replaces the current process image in
+// place, so self-reexec deployments fall back to the Windows SCM restart.
+func NewSelfReexecServiceManager() ServiceManager {
+	return NewServiceManager()
+}
@@ -0,0 +1,72 @@
+//go:build windows
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+	"time"
+)
+
+// windowsServiceName is the name under which pgSCV is registered with the
+// Windows Service Control Manager.
+const windowsServiceName = "weaponry-agent"
+
+// NewServiceManager returns a ServiceManager that restarts pgSCV through
+// the Windows Service Control Manager.
+func NewServiceManager() ServiceManager {
+	return windowsServiceManager{}
+}
+
+// windowsServiceManager restarts the agent via the Windows SCM.
+type windowsServiceManager struct{}
+
+// Restart implements ServiceManager.
+func (windowsServiceManager) Restart(ctx context.Context) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service control manager: %s", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(windowsServiceName)
+	if err != nil {
+		return fmt.Errorf("open service %q: %s", windowsServiceName, err)
+	}
+	defer s.Close()
+
+	if _, err := s.Control(svc.Stop); err != nil {
+		return fmt.Errorf("stop service %q: %s", windowsServiceName, err)
+	}
+	if err := waitForState(ctx, s, svc.Stopped); err != nil {
+		return err
+	}
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("start service %q: %s", windowsServiceName, err)
+	}
+	return nil
+}
+
+// waitForState polls the service until it reaches want, ctx expires, or
+// 30 seconds pass.
+func waitForState(ctx context.Context, s *mgr.Service, want svc.State) error {
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		status, err := s.Query()
+		if err != nil {
+			return fmt.Errorf("query service status: %s", err)
+		}
+		if status.State == want {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("timed out waiting for service to reach state %v", want)
+}
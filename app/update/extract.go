@@ -0,0 +1,34 @@
+package update
+
+import (
+	"fmt"
+	"github.com/weaponry/pgscv/app/archive"
+	"os"
+)
+
+// maxExtractedSize and maxExtractedFileSize bound how large a single
+// distribution archive is allowed to expand to, guarding against
+// zip-bomb-style resource exhaustion from a compromised or malicious
+// mirror. pgSCV distributions are a single binary a few tens of MiB in
+// size, so these limits leave plenty of headroom without being unbounded.
+const (
+	maxExtractedSize     = 512 << 20 // 512 MiB
+	maxExtractedFileSize = 256 << 20 // 256 MiB
+)
+
+// extract unpacks distFile (gzip/xz/zstd auto-detected) into destDir.
+func extract(distFile, destDir string) error {
+	f, err := os.Open(distFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := archive.Extract(f, destDir, archive.ExtractOptions{
+		MaxTotalSize: maxExtractedSize,
+		MaxFileSize:  maxExtractedFileSize,
+	}); err != nil {
+		return fmt.Errorf("extract %s: %s", distFile, err)
+	}
+	return nil
+}
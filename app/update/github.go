@@ -0,0 +1,163 @@
+package update
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/weaponry/pgscv/app/xfer"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// githubAssetTemplate is the naming convention pgSCV release assets use.
+// {{.GOOS}}/{{.GOARCH}} are substituted with runtime.GOOS/runtime.GOARCH.
+const githubAssetTemplate = "pgscv_{{.GOOS}}_{{.GOARCH}}.tar.gz"
+
+type githubRelease struct {
+	TagName string        `json:"tag_name"`
+	Assets  []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	Size               int64  `json:"size"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// githubSource fetches pgSCV releases published to GitHub Releases,
+// picking the asset that matches the running GOOS/GOARCH and verifying it
+// against the release's checksums.txt asset.
+type githubSource struct {
+	repo       string // "owner/repo"
+	client     *http.Client
+	downloader *xfer.Downloader
+}
+
+// NewGitHubSource returns a Source backed by the GitHub Releases API for
+// repo ("owner/repo"); an empty repo defaults to the upstream project.
+func NewGitHubSource(repo string) Source {
+	if repo == "" {
+		repo = "weaponry/pgscv"
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &githubSource{repo: repo, client: client, downloader: xfer.NewDownloader(client, 5, 0)}
+}
+
+func (s *githubSource) latestRelease(ctx context.Context) (githubRelease, error) {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", s.repo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return githubRelease{}, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return githubRelease{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return githubRelease{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var rel githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return githubRelease{}, err
+	}
+	return rel, nil
+}
+
+// Latest implements Source.
+func (s *githubSource) Latest(ctx context.Context) (string, error) {
+	rel, err := s.latestRelease(ctx)
+	if err != nil {
+		return "", fmt.Errorf("github releases: %s", err)
+	}
+	return strings.TrimPrefix(rel.TagName, "v"), nil
+}
+
+// Fetch implements Source.
+func (s *githubSource) Fetch(ctx context.Context, version string, destDir string) (string, error) {
+	rel, err := s.latestRelease(ctx)
+	if err != nil {
+		return "", fmt.Errorf("github releases: %s", err)
+	}
+	if got := strings.TrimPrefix(rel.TagName, "v"); got != version {
+		return "", fmt.Errorf("requested version %s is no longer the latest release (now %s)", version, got)
+	}
+
+	assetName := assetNameFor(githubAssetTemplate)
+	var assetURL, checksumsURL string
+	var assetSize int64
+	for _, a := range rel.Assets {
+		switch a.Name {
+		case assetName:
+			assetURL = a.BrowserDownloadURL
+			assetSize = a.Size
+		case "checksums.txt":
+			checksumsURL = a.BrowserDownloadURL
+		}
+	}
+	if assetURL == "" {
+		return "", fmt.Errorf("release %s has no asset named %q", rel.TagName, assetName)
+	}
+
+	wantSum, err := s.checksumFor(ctx, checksumsURL, assetName)
+	if err != nil {
+		return "", err
+	}
+
+	distFile := destDir + "/" + assetName
+	gotSum, err := s.downloader.Download(ctx, assetURL, distFile, assetSize, logProgress(assetName))
+	if err != nil {
+		os.Remove(distFile)
+		return "", fmt.Errorf("download release asset: %s", err)
+	}
+	defer os.Remove(distFile)
+
+	if gotSum != wantSum {
+		return "", fmt.Errorf("download release asset: checksum mismatch, want %s, got %s", wantSum, gotSum)
+	}
+
+	if err := extract(distFile, destDir); err != nil {
+		return "", fmt.Errorf("extract release asset: %s", err)
+	}
+	return destDir + "/" + fileBinary, nil
+}
+
+func (s *githubSource) checksumFor(ctx context.Context, checksumsURL, assetName string) (string, error) {
+	if checksumsURL == "" {
+		return "", fmt.Errorf("release has no checksums.txt asset")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checksumsURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(resp.Body); err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(buf.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == assetName {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("checksums.txt has no entry for %s", assetName)
+}
+
+func assetNameFor(tmpl string) string {
+	name := strings.ReplaceAll(tmpl, "{{.GOOS}}", runtime.GOOS)
+	return strings.ReplaceAll(name, "{{.GOARCH}}", runtime.GOARCH)
+}
@@ -0,0 +1,132 @@
+package update
+
+import (
+	"context"
+	"fmt"
+	"github.com/weaponry/pgscv/app/distsign"
+	"github.com/weaponry/pgscv/app/xfer"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	stableDistUpgradeBaseURL  = "https://dist.weaponry.io"
+	stagingDistUpgradeBaseURL = "https://dist.wpnr.brcd.pro"
+	developmentDistUpgradeURL = "http://127.0.0.1:2080"
+
+	fileManifest     = "weaponry-agent.json.sig"
+	fileDistribution = "weaponry-agent.tar.gz"
+	fileBinary       = "weaponry-agent"
+)
+
+// httpSource is the original pgSCV distribution layout: a bespoke HTTP
+// directory serving a signed manifest and a matching tarball per channel.
+type httpSource struct {
+	baseURL    string
+	client     *http.Client
+	downloader *xfer.Downloader
+
+	mu       sync.Mutex
+	manifest *distsign.Manifest // cached result of the last Latest call
+}
+
+// distBaseURL maps a release channel to the dist.weaponry.io host that
+// serves it. It is shared by every source that tracks a channel off the
+// bespoke distribution host rather than a caller-supplied repoURL (the
+// HTTP source itself, and the apt/yum sources' repository mirrors).
+func distBaseURL(channel string) string {
+	switch channel {
+	case "release", "stable", "":
+		return stableDistUpgradeBaseURL
+	case "master", "beta":
+		return stagingDistUpgradeBaseURL
+	default:
+		return developmentDistUpgradeURL
+	}
+}
+
+// NewHTTPSource returns a Source for the bespoke dist.weaponry.io HTTP
+// layout. repoURL, when set, overrides the channel-derived base URL so
+// operators can point at a private mirror.
+func NewHTTPSource(channel, repoURL string) Source {
+	baseURL := repoURL
+	if baseURL == "" {
+		baseURL = distBaseURL(channel)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &httpSource{
+		baseURL:    baseURL,
+		client:     client,
+		downloader: xfer.NewDownloader(client, 5, 0),
+	}
+}
+
+// Latest implements Source.
+func (s *httpSource) Latest(ctx context.Context) (string, error) {
+	sm, err := distsign.FetchSignedManifest(ctx, s.client, s.baseURL+"/"+fileManifest)
+	if err != nil {
+		return "", fmt.Errorf("fetch manifest: %s", err)
+	}
+	manifest, err := distsign.VerifyPinned(sm)
+	if err != nil {
+		return "", fmt.Errorf("verify manifest: %s", err)
+	}
+
+	s.mu.Lock()
+	s.manifest = &manifest
+	s.mu.Unlock()
+
+	return manifest.Version, nil
+}
+
+// Fetch implements Source.
+func (s *httpSource) Fetch(ctx context.Context, version, destDir string) (string, error) {
+	manifest, err := s.manifestFor(ctx, version)
+	if err != nil {
+		return "", err
+	}
+
+	distFile := destDir + "/" + fileDistribution
+	sum, err := s.downloader.Download(ctx, s.baseURL+"/"+manifest.Filename, distFile, manifest.Length, logProgress(manifest.Filename))
+	if err != nil {
+		os.Remove(distFile)
+		return "", fmt.Errorf("download distribution: %s", err)
+	}
+	defer os.Remove(distFile)
+
+	if fi, err := os.Stat(distFile); err != nil || fi.Size() != manifest.Length {
+		return "", fmt.Errorf("download distribution: got %d bytes, manifest declares %d", statSize(fi), manifest.Length)
+	}
+	if sum != manifest.SHA256 {
+		return "", fmt.Errorf("download distribution: checksum mismatch, want %s, got %s", manifest.SHA256, sum)
+	}
+
+	if err := extract(distFile, destDir); err != nil {
+		return "", fmt.Errorf("extract distribution: %s", err)
+	}
+	return destDir + "/" + fileBinary, nil
+}
+
+// manifestFor returns a verified manifest for version, reusing the one
+// cached by Latest when it already matches.
+func (s *httpSource) manifestFor(ctx context.Context, version string) (distsign.Manifest, error) {
+	s.mu.Lock()
+	cached := s.manifest
+	s.mu.Unlock()
+
+	if cached != nil && cached.Version == version {
+		return *cached, nil
+	}
+	if _, err := s.Latest(ctx); err != nil {
+		return distsign.Manifest{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.manifest == nil || s.manifest.Version != version {
+		return distsign.Manifest{}, fmt.Errorf("requested version %s is no longer the latest available", version)
+	}
+	return *s.manifest, nil
+}
@@ -0,0 +1,41 @@
+package update
+
+import (
+	"github.com/rs/zerolog/log"
+	"github.com/weaponry/pgscv/app/xfer"
+	"os"
+	"time"
+)
+
+// logProgress returns an xfer.Progress that logs download progress and
+// throughput for name at most once a second (plus once more when done
+// reaches total), so a slow transfer shows up in the logs without
+// spamming them.
+func logProgress(name string) xfer.Progress {
+	var last time.Time
+	start := time.Now()
+	return func(done, total int64) {
+		now := time.Now()
+		if now.Sub(last) < time.Second && done != total {
+			return
+		}
+		last = now
+		var mbps float64
+		if elapsed := now.Sub(start).Seconds(); elapsed > 0 {
+			mbps = float64(done) / elapsed / (1 << 20)
+		}
+		if total > 0 {
+			log.Debug().Msgf("downloading %s: %d/%d bytes (%.0f%%, %.2f MB/s)", name, done, total, 100*float64(done)/float64(total), mbps)
+		} else {
+			log.Debug().Msgf("downloading %s: %d bytes (%.2f MB/s)", name, done, mbps)
+		}
+	}
+}
+
+// statSize returns fi.Size(), or -1 if fi is nil (the stat itself failed).
+func statSize(fi os.FileInfo) int64 {
+	if fi == nil {
+		return -1
+	}
+	return fi.Size()
+}
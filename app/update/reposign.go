@@ -0,0 +1,100 @@
+package update
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"io"
+)
+
+// distPublicKeyArmored is the ASCII-armored OpenPGP public key pgSCV
+// bundles to verify apt/yum repository metadata on its own, independently
+// of whatever (if anything) the host's own /etc/apt/trusted.gpg.d or
+// /etc/pki/rpm-gpg has configured. Its private half lives offline next to
+// the distsign root key (see app/distsign) and signs Release/repomd.xml
+// files at publish time.
+const distPublicKeyArmored = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mQGNBGpk5zIBDAC2GBoURWyMUY2HxQx+dWK7fIbnK3QO+w/387Oeo8FZUQqPs203
+bvDKxpKJFIo9u5RJX+gGHLUH9MwpMSFLYANRWexHEJ+LzkC5nDMSus3FOucX7PPK
+1nmmffTIYKAY3I6G84rQYA5jczf3RrwvwlacND8nIDf/7NyzxXe146pNdqv87zIO
+9Vq2waK7hUS6zdTkdPhnbovMkd7EoC7H81L7hY/727M7hx7hf6MzvKEZElPA3wwZ
+9XXIhXcbOXBuDn/wJXGjM2CpUNM+zBpF6sZkKDUimFMu6i0AYgQtP/DUkcoZldxo
+tTHHV9w5qCFsXBAwIWt2GEN95dBPN+D7batG2evnoREBx2OnpFDSF2uFRAWid1yO
+jpRJnYez2y8UPXBQGF+9dKbb1XWJx024xrTKJgGEX8U/4otOvlO/jK32Wzkd2+k9
+5X2umMhaC8eXXPSjM3ytRSmHN6cBeFrpKOciU7mK96ZwP4IguA3QdOZ+poSDohBO
+KaxQJ8xUnflK5lcAEQEAAbQuV2VhcG9ucnkgQWdlbnQgRGlzdHJpYnV0aW9uIDxk
+aXN0QHdlYXBvbnJ5LmlvPokBzgQTAQoAOBYhBKjgpQ+jijwq6Lf4Cehy+3/2VKNt
+BQJqZOcyAhsDBQsJCAcCBhUKCQgLAgQWAgMBAh4BAheAAAoJEOhy+3/2VKNtwg0L
+/RFluA+rC3ZEKjb/LdisId3mzC9hhRSGxit08s8OLMHKmqa2rN28v2KMSOP2M0li
+QfPtd8zupsK725c2/9zupDe/8Zh1wWQQ0x5qSqymdS5opLWDO+Rj2uU9IWF52eIE
+obKOWS0yYM9ZnJzzOFwJBch202xhMp2R2ZkgadXsLNmTkroc9d/Ra2rlyGgGXkNu
+UmiM3/4hFlYU8fR62GuLovHMf3Th1GnoVBCGNuep4mdD2YoNsHUJybkUD3S/36f3
+NkkfsIt2p5zAYPkiV3sbiJWEl80/sKCOgvV6/GmBnqSgkrRqEve+pK3M1S5ITAvX
+HRU+vGOBTB6vrKAkGduLtBhUYitaMxYU/t4hQeLUhD50z+YEWXaz9hwT9FyPLpAE
+nPgHg8ryFqLsOgVr3b98vV96KpJyI4kSpk8haYHJUnBNHb9Bcv8WMpPsA561gSnO
+TebQcvvXLOF8T+P0JZZ+6c9gmef2gizdTnvuTc5YgnyUAUfQYBLUoyoKgZmSAIxh
+eg==
+=UVC+
+-----END PGP PUBLIC KEY BLOCK-----`
+
+// distKeyRing parses the bundled distribution signing key once per call;
+// it is cheap enough that callers don't need to cache it.
+func distKeyRing() (openpgp.EntityList, error) {
+	ring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader([]byte(distPublicKeyArmored)))
+	if err != nil {
+		return nil, fmt.Errorf("update: parse bundled repository signing key: %s", err)
+	}
+	return ring, nil
+}
+
+// verifyDetachedSignature checks that sig is a valid detached OpenPGP
+// signature over data made by the bundled distribution signing key, e.g.
+// a yum repo's repomd.xml against repomd.xml.asc. sig may be either
+// ASCII-armored (as repomd.xml.asc ships) or raw binary.
+func verifyDetachedSignature(data, sig []byte) error {
+	ring, err := distKeyRing()
+	if err != nil {
+		return err
+	}
+	if _, err := openpgp.CheckDetachedSignature(ring, bytes.NewReader(data), signatureBody(sig)); err != nil {
+		return fmt.Errorf("update: repository metadata has an invalid signature: %s", err)
+	}
+	return nil
+}
+
+// signatureBody returns a reader over the raw OpenPGP signature packet(s)
+// in sig, transparently un-armoring it when it's ASCII-armored.
+func signatureBody(sig []byte) io.Reader {
+	if block, err := armor.Decode(bytes.NewReader(sig)); err == nil {
+		return block.Body
+	}
+	return bytes.NewReader(sig)
+}
+
+// verifyClearsign checks a clearsigned document (e.g. apt's InRelease)
+// against the bundled distribution signing key and returns the signed
+// plaintext.
+func verifyClearsign(data []byte) ([]byte, error) {
+	block, _ := clearsign.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("update: repository metadata is not a clearsigned document")
+	}
+	ring, err := distKeyRing()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := openpgp.CheckDetachedSignature(ring, bytes.NewReader(block.Bytes), block.ArmoredSignature.Body); err != nil {
+		return nil, fmt.Errorf("update: repository metadata has an invalid signature: %s", err)
+	}
+	return block.Plaintext, nil
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
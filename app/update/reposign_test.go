@@ -0,0 +1,71 @@
+package update
+
+import (
+	"os"
+	"testing"
+)
+
+func TestVerifyClearsign(t *testing.T) {
+	data, err := os.ReadFile("testdata/InRelease.txt")
+	if err != nil {
+		t.Fatalf("read fixture: %s", err)
+	}
+
+	plain, err := verifyClearsign(data)
+	if err != nil {
+		t.Fatalf("verifyClearsign: unexpected error: %s", err)
+	}
+	if len(plain) == 0 {
+		t.Fatal("verifyClearsign: got empty plaintext")
+	}
+}
+
+func TestVerifyClearsign_Tampered(t *testing.T) {
+	data, err := os.ReadFile("testdata/InRelease.txt")
+	if err != nil {
+		t.Fatalf("read fixture: %s", err)
+	}
+
+	tampered := append([]byte(nil), data...)
+	for i, b := range tampered {
+		if b == '0' {
+			tampered[i] = '1'
+			break
+		}
+	}
+
+	if _, err := verifyClearsign(tampered); err == nil {
+		t.Fatal("verifyClearsign: expected error for tampered document, got nil")
+	}
+}
+
+func TestVerifyDetachedSignature(t *testing.T) {
+	data, err := os.ReadFile("testdata/repomd.xml")
+	if err != nil {
+		t.Fatalf("read fixture: %s", err)
+	}
+	sig, err := os.ReadFile("testdata/repomd.xml.asc")
+	if err != nil {
+		t.Fatalf("read fixture: %s", err)
+	}
+
+	if err := verifyDetachedSignature(data, sig); err != nil {
+		t.Fatalf("verifyDetachedSignature: unexpected error: %s", err)
+	}
+}
+
+func TestVerifyDetachedSignature_Tampered(t *testing.T) {
+	data, err := os.ReadFile("testdata/repomd.xml")
+	if err != nil {
+		t.Fatalf("read fixture: %s", err)
+	}
+	sig, err := os.ReadFile("testdata/repomd.xml.asc")
+	if err != nil {
+		t.Fatalf("read fixture: %s", err)
+	}
+
+	tampered := append(data, '\n')
+	if err := verifyDetachedSignature(tampered, sig); err == nil {
+		t.Fatal("verifyDetachedSignature: expected error for tampered data, got nil")
+	}
+}
@@ -0,0 +1,61 @@
+// Package update provides pluggable sources for discovering and
+// retrieving new pgSCV releases, so operators can point the agent at the
+// bespoke HTTP distribution, a Debian/RPM repository, a GitHub Releases
+// feed, or an OCI registry without recompiling.
+package update
+
+import "context"
+
+// Source discovers and retrieves new pgSCV releases from a single
+// distribution channel.
+type Source interface {
+	// Latest returns the newest version this source currently offers.
+	Latest(ctx context.Context) (string, error)
+
+	// Fetch retrieves version and makes its binary available at a path
+	// inside destDir, which the caller then hands to a BinaryInstaller.
+	// Sources that install through a system package manager (apt, yum)
+	// perform the whole install themselves, including any
+	// postinst-driven service restart, and return an empty path to tell
+	// the caller there is nothing left to install.
+	Fetch(ctx context.Context, version, destDir string) (binaryPath string, err error)
+}
+
+// Config selects a Source and parameterizes it. It mirrors the
+// UpdateSource/UpdateChannel/UpdateRepoURL fields on app.Config, which are
+// passed straight through to New.
+type Config struct {
+	// Source is one of "http" (default), "apt", "yum", "github" or "oci".
+	Source string
+	// Channel is the release channel to track, e.g. "stable" or "beta".
+	// Its meaning is source-specific: the HTTP source maps it to a base
+	// URL, apt uses it as the suite name (dists/<suite>), and apt/yum use
+	// it to pick the default mirror host when RepoURL is unset. GitHub/OCI
+	// sources ignore it; a channel-specific tag goes in RepoURL instead.
+	Channel string
+	// RepoURL overrides the source's default location, e.g. a private
+	// apt mirror, a "owner/repo" GitHub slug, or an OCI reference.
+	RepoURL string
+}
+
+// New returns the Source selected by cfg.
+func New(cfg Config) (Source, error) {
+	switch cfg.Source {
+	case "", "http":
+		return NewHTTPSource(cfg.Channel, cfg.RepoURL), nil
+	case "apt":
+		return NewAptSource(cfg.Channel, cfg.RepoURL), nil
+	case "yum":
+		return NewYumSource(cfg.Channel, cfg.RepoURL), nil
+	case "github":
+		return NewGitHubSource(cfg.RepoURL), nil
+	case "oci":
+		return NewOCISource(cfg.RepoURL), nil
+	default:
+		return nil, errUnknownSource(cfg.Source)
+	}
+}
+
+type errUnknownSource string
+
+func (e errUnknownSource) Error() string { return "update: unknown source " + string(e) }
@@ -0,0 +1,25 @@
+package xfer
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+	"io"
+)
+
+// rateLimitedReader throttles Read against a token-bucket limiter, one
+// token per byte read.
+type rateLimitedReader struct {
+	ctx     context.Context
+	r       io.Reader
+	limiter *rate.Limiter
+}
+
+func (rl *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := rl.r.Read(p)
+	if n > 0 {
+		if werr := rl.limiter.WaitN(rl.ctx, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
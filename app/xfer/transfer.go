@@ -0,0 +1,140 @@
+package xfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+)
+
+// retryableErr marks an error from a single attempt as worth retrying.
+type retryableErr struct{ err error }
+
+func (e retryableErr) Error() string { return e.err.Error() }
+func (e retryableErr) Unwrap() error { return e.err }
+
+func isRetryable(err error) bool {
+	var r retryableErr
+	return errors.As(err, &r)
+}
+
+// backoff returns an exponential delay with jitter for the given attempt
+// (1-indexed). The base delay is capped at 30s before up to 50% jitter is
+// added, so the result never exceeds 45s.
+func backoff(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt-1)) * 200 * time.Millisecond
+	if base > 30*time.Second {
+		base = 30 * time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(base)/2 + 1))
+	return base + jitter
+}
+
+// attempt performs a single GET, resuming from a previous "*.part" file
+// via a Range request when one exists, and appends to partFile as it
+// streams the response. maxLen > 0 caps the total bytes ever written to
+// partFile (across resumes) at maxLen+1, so a compromised or misbehaving
+// server can't fill the disk before the caller's post-download length
+// check runs.
+func (d *Downloader) attempt(ctx context.Context, url, partFile string, maxLen int64, onProgress Progress) error {
+	var resumeFrom int64
+	if fi, err := os.Stat(partFile); err == nil {
+		resumeFrom = fi.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if resumeFrom > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resumeFrom))
+	}
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return retryableErr{err}
+	}
+	defer resp.Body.Close()
+
+	var (
+		out   *os.File
+		total int64
+	)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		resumeFrom = 0
+		if out, err = os.Create(partFile); err != nil {
+			return err
+		}
+		if resp.ContentLength >= 0 {
+			total = resp.ContentLength
+		}
+	case http.StatusPartialContent:
+		if out, err = os.OpenFile(partFile, os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+			return err
+		}
+		if resp.ContentLength >= 0 {
+			total = resumeFrom + resp.ContentLength
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// the part file we asked to resume from is stale or already complete
+		_ = os.Remove(partFile)
+		return retryableErr{fmt.Errorf("range not satisfiable, restarting")}
+	default:
+		if resp.StatusCode >= 500 {
+			return retryableErr{fmt.Errorf("server error: %s", resp.Status)}
+		}
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	defer out.Close()
+
+	var body io.Reader = resp.Body
+	if maxLen > 0 {
+		remaining := maxLen - resumeFrom
+		if remaining < 0 {
+			remaining = 0
+		}
+		body = io.LimitReader(body, remaining+1)
+	}
+	if d.Limiter != nil {
+		body = &rateLimitedReader{ctx: ctx, r: body, limiter: d.Limiter}
+	}
+
+	done := resumeFrom
+	if onProgress != nil {
+		onProgress(done, total)
+	}
+
+	bufSize := 32 * 1024
+	if d.Limiter != nil {
+		if burst := d.Limiter.Burst(); burst > 0 && burst < bufSize {
+			bufSize = burst
+		}
+	}
+	buf := make([]byte, bufSize)
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			if _, werr := out.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			done += int64(n)
+			if onProgress != nil {
+				onProgress(done, total)
+			}
+			if maxLen > 0 && done > maxLen {
+				return fmt.Errorf("download exceeds declared length of %d bytes", maxLen)
+			}
+		}
+		if rerr == io.EOF {
+			return nil
+		}
+		if rerr != nil {
+			return retryableErr{rerr}
+		}
+	}
+}